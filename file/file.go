@@ -0,0 +1,11 @@
+// Package file provides small filesystem helpers shared across the module.
+package file
+
+import "os"
+
+// Exists returns true if the given path exists on disk, regardless of
+// whether it is a file or a directory.
+func Exists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
@@ -0,0 +1,34 @@
+package file
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+func TestExists(t *testing.T) {
+	dir := t.TempDir()
+
+	present := filepath.Join(dir, "present.txt")
+	if err := ioutil.WriteFile(present, []byte("x"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		name string
+		path string
+		want bool
+	}{
+		{"existing file", present, true},
+		{"existing directory", dir, true},
+		{"missing path", filepath.Join(dir, "missing.txt"), false},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if got := Exists(c.path); got != c.want {
+				t.Errorf("Exists(%q) = %v, want %v", c.path, got, c.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,23 @@
+// Package jsonconfig reads a JSON configuration file from disk into a
+// caller-provided struct.
+package jsonconfig
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+)
+
+// Load reads configFile and unmarshals its contents into v.
+func Load(configFile string, v interface{}) error {
+	if len(configFile) == 0 {
+		return fmt.Errorf("jsonconfig: no configuration file specified")
+	}
+
+	b, err := ioutil.ReadFile(configFile)
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(b, v)
+}
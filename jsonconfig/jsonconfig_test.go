@@ -0,0 +1,56 @@
+package jsonconfig
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoad(t *testing.T) {
+	type config struct {
+		Name string `json:"Name"`
+	}
+
+	t.Run("no configuration file specified", func(t *testing.T) {
+		var c config
+		if err := Load("", &c); err == nil {
+			t.Fatal("expected an error for an empty configFile, got nil")
+		}
+	})
+
+	t.Run("missing file", func(t *testing.T) {
+		var c config
+		if err := Load(filepath.Join(t.TempDir(), "missing.json"), &c); err == nil {
+			t.Fatal("expected an error for a missing file, got nil")
+		}
+	})
+
+	t.Run("valid file", func(t *testing.T) {
+		dir := t.TempDir()
+		path := filepath.Join(dir, "config.json")
+		if err := ioutil.WriteFile(path, []byte(`{"Name": "widget"}`), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		var c config
+		if err := Load(path, &c); err != nil {
+			t.Fatalf("Load() error: %v", err)
+		}
+		if c.Name != "widget" {
+			t.Errorf("c.Name = %q, want %q", c.Name, "widget")
+		}
+	})
+
+	t.Run("invalid JSON", func(t *testing.T) {
+		dir := t.TempDir()
+		path := filepath.Join(dir, "config.json")
+		if err := ioutil.WriteFile(path, []byte(`{not json`), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		var c config
+		if err := Load(path, &c); err == nil {
+			t.Fatal("expected an error for invalid JSON, got nil")
+		}
+	})
+}
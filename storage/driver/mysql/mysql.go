@@ -0,0 +1,86 @@
+// Package mysql holds the MySQL connection information and the low-level
+// helpers used to open and provision a database.
+package mysql
+
+import (
+	"fmt"
+
+	_ "github.com/go-sql-driver/mysql"
+	"github.com/jmoiron/sqlx"
+)
+
+// Info contains the information needed to connect to a MySQL database.
+type Info struct {
+	Host            string `json:"Host"`
+	Port            string `json:"Port"`
+	Username        string `json:"Username"`
+	Password        string `json:"Password"`
+	Database        string `json:"Database"`
+	Parameter       string `json:"Parameter"`
+	MigrationFolder string `json:"MigrationFolder"`
+
+	// MigrationTable is the name of the table used to track applied
+	// migrations. It defaults to "migration" and may be schema-qualified
+	// (e.g. "app1.migration") so multiple apps can share a database without
+	// colliding.
+	MigrationTable string `json:"MigrationTable"`
+
+	// GhostBinary is the path to the gh-ost binary used to run migration
+	// files annotated with "-- +migrate online table=<name>". Defaults to
+	// "gh-ost" (resolved from PATH) when left blank.
+	GhostBinary string `json:"GhostBinary"`
+
+	// GhostChunkSize overrides gh-ost's --chunk-size. Left unset, gh-ost's
+	// own default is used.
+	GhostChunkSize int `json:"GhostChunkSize"`
+
+	// GhostMaxLoad overrides gh-ost's --max-load throttle
+	// (e.g. "Threads_running=25").
+	GhostMaxLoad string `json:"GhostMaxLoad"`
+
+	// GhostReplicaHost, if set, points gh-ost at a replica to read from via
+	// --assume-master-host instead of connecting directly to the master.
+	// It's a bare host[:port], the same form gh-ost's own flag expects --
+	// not a DSN.
+	GhostReplicaHost string `json:"GhostReplicaHost"`
+
+	// ServerVersion, if set, skips the "SELECT VERSION()" probe used to
+	// decide whether MySQL 8 DDL (utf8mb4, no 767-byte index workaround)
+	// can be used.
+	ServerVersion string `json:"ServerVersion"`
+
+	// Charset is the connection and table charset. Defaults to "utf8mb4".
+	Charset string `json:"Charset"`
+
+	// Collation is the connection and table collation. Defaults to
+	// "utf8mb4_unicode_ci".
+	Collation string `json:"Collation"`
+}
+
+// dsn returns the data source name used to connect, optionally scoped to the
+// configured database.
+func (i *Info) dsn(withDatabase bool) string {
+	database := ""
+	if withDatabase {
+		database = i.Database
+	}
+
+	parameter := i.Parameter
+	if len(i.Charset) > 0 {
+		parameter = fmt.Sprintf("%v&charset=%v,utf8&collation=%v", parameter, i.Charset, i.Collation)
+	}
+
+	return fmt.Sprintf("%v:%v@tcp(%v:%v)/%v?%v", i.Username, i.Password, i.Host, i.Port, database, parameter)
+}
+
+// Connect opens a connection to the database, optionally selecting the
+// configured database.
+func (i *Info) Connect(withDatabase bool) (*sqlx.DB, error) {
+	return sqlx.Connect("mysql", i.dsn(withDatabase))
+}
+
+// Create creates the configured database using an existing connection.
+func (i *Info) Create(con *sqlx.DB) error {
+	_, err := con.Exec(fmt.Sprintf("CREATE DATABASE IF NOT EXISTS %v;", i.Database))
+	return err
+}
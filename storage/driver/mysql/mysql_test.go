@@ -0,0 +1,39 @@
+package mysql
+
+import "testing"
+
+func TestDSN(t *testing.T) {
+	cases := []struct {
+		name string
+		info Info
+		with bool
+		want string
+	}{
+		{
+			name: "no charset, with database",
+			info: Info{Username: "root", Password: "secret", Host: "127.0.0.1", Port: "3306", Database: "app", Parameter: "parseTime=true"},
+			with: true,
+			want: "root:secret@tcp(127.0.0.1:3306)/app?parseTime=true",
+		},
+		{
+			name: "no charset, without database",
+			info: Info{Username: "root", Password: "secret", Host: "127.0.0.1", Port: "3306", Database: "app", Parameter: "parseTime=true"},
+			with: false,
+			want: "root:secret@tcp(127.0.0.1:3306)/?parseTime=true",
+		},
+		{
+			name: "charset adds charset and collation parameters",
+			info: Info{Username: "root", Password: "secret", Host: "127.0.0.1", Port: "3306", Database: "app", Parameter: "parseTime=true", Charset: "utf8mb4", Collation: "utf8mb4_unicode_ci"},
+			with: true,
+			want: "root:secret@tcp(127.0.0.1:3306)/app?parseTime=true&charset=utf8mb4,utf8&collation=utf8mb4_unicode_ci",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if got := c.info.dsn(c.with); got != c.want {
+				t.Errorf("dsn(%v) = %q, want %q", c.with, got, c.want)
+			}
+		})
+	}
+}
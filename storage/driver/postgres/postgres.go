@@ -0,0 +1,49 @@
+// Package postgres holds the PostgreSQL connection information and the
+// low-level helpers used to open and provision a database.
+package postgres
+
+import (
+	"fmt"
+
+	"github.com/jmoiron/sqlx"
+	_ "github.com/lib/pq"
+)
+
+// Info contains the information needed to connect to a PostgreSQL database.
+type Info struct {
+	Host            string `json:"Host"`
+	Port            string `json:"Port"`
+	Username        string `json:"Username"`
+	Password        string `json:"Password"`
+	Database        string `json:"Database"`
+	SSLMode         string `json:"SSLMode"`
+	MigrationFolder string `json:"MigrationFolder"`
+}
+
+// dsn returns the data source name used to connect, optionally scoped to the
+// configured database.
+func (i *Info) dsn(withDatabase bool) string {
+	database := "postgres"
+	if withDatabase {
+		database = i.Database
+	}
+
+	sslMode := i.SSLMode
+	if len(sslMode) == 0 {
+		sslMode = "disable"
+	}
+
+	return fmt.Sprintf("host=%v port=%v user=%v password=%v dbname=%v sslmode=%v", i.Host, i.Port, i.Username, i.Password, database, sslMode)
+}
+
+// Connect opens a connection to the database, optionally selecting the
+// configured database.
+func (i *Info) Connect(withDatabase bool) (*sqlx.DB, error) {
+	return sqlx.Connect("postgres", i.dsn(withDatabase))
+}
+
+// Create creates the configured database using an existing connection.
+func (i *Info) Create(con *sqlx.DB) error {
+	_, err := con.Exec(fmt.Sprintf("CREATE DATABASE %v;", i.Database))
+	return err
+}
@@ -0,0 +1,19 @@
+// Package sqlite holds the SQLite connection information and the low-level
+// helpers used to open a database file.
+package sqlite
+
+import (
+	"github.com/jmoiron/sqlx"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// Info contains the information needed to connect to a SQLite database.
+type Info struct {
+	Path            string `json:"Path"`
+	MigrationFolder string `json:"MigrationFolder"`
+}
+
+// Connect opens the database file, creating it if it does not yet exist.
+func (i *Info) Connect() (*sqlx.DB, error) {
+	return sqlx.Connect("sqlite3", i.Path)
+}
@@ -0,0 +1,360 @@
+// Package auto generates MySQL schema migrations from registered Go entity
+// structs.
+//
+// Callers register the structs that back their tables with Register, using
+// the same `db:"..."` tags already understood by storage/migration/mysql's
+// Entity. Configuration.Generate then connects to the live database,
+// compares information_schema against the registered structs, and writes a
+// timestamped .up.sql/.down.sql pair into the configured migration folder
+// for anything that is missing or out of date. Dropped columns are never
+// applied automatically; they are written to a separate *.manual.sql file,
+// in a "manual" subfolder so storage/migration never tries to apply it,
+// for an operator to review.
+package auto
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	database "github.com/stefanwuthrich/core/storage/driver/mysql"
+)
+
+// *****************************************************************************
+// Registration
+// *****************************************************************************
+
+var (
+	registeredMutex sync.Mutex
+	registered      []interface{}
+)
+
+// Register adds v to the set of structs considered by Generate. v should be
+// a pointer to a struct whose fields carry `db:"..."` tags, the same
+// convention used by storage/migration/mysql.Entity. The table name is
+// derived from the struct's type name, lower-cased (e.g. *UserAccount ->
+// "user_account").
+func Register(v interface{}) {
+	registeredMutex.Lock()
+	registered = append(registered, v)
+	registeredMutex.Unlock()
+}
+
+// Reset clears the set of registered structs. Intended for tests.
+func Reset() {
+	registeredMutex.Lock()
+	registered = nil
+	registeredMutex.Unlock()
+}
+
+// *****************************************************************************
+// Configuration
+// *****************************************************************************
+
+// Configuration wraps the MySQL connection information used to introspect
+// the live schema.
+type Configuration struct {
+	database.Info
+}
+
+// *****************************************************************************
+// Struct mapping
+// *****************************************************************************
+
+// field describes a single mapped struct field.
+type field struct {
+	Name    string // column name, from the db tag
+	SQLType string // target MySQL column type
+	Primary bool   // true for the "id"/"ID" convention field
+}
+
+// mappedTable describes a struct mapped to a table.
+type mappedTable struct {
+	Name   string
+	Fields []field
+}
+
+// tableName derives a table name from a registered struct's type name.
+func tableName(v interface{}) string {
+	t := reflect.TypeOf(v)
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	var b strings.Builder
+	for i, r := range t.Name() {
+		if i > 0 && r >= 'A' && r <= 'Z' {
+			b.WriteByte('_')
+		}
+		b.WriteRune(r)
+	}
+
+	return strings.ToLower(b.String())
+}
+
+// mapField maps a single struct field to a column definition using the
+// common Go -> MySQL conventions: string -> VARCHAR(191)/TEXT, uint32 ->
+// INT UNSIGNED, time.Time -> TIMESTAMP, []byte -> BLOB. A `size:"..."`
+// struct tag overrides the default VARCHAR length, or selects TEXT when set
+// to "text".
+func mapField(sf reflect.StructField) (field, bool) {
+	name := sf.Tag.Get("db")
+	if len(name) == 0 || name == "-" {
+		return field{}, false
+	}
+
+	size := sf.Tag.Get("size")
+
+	var sqlType string
+	switch {
+	case sf.Type.Kind() == reflect.String && size == "text":
+		sqlType = "TEXT"
+	case sf.Type.Kind() == reflect.String && len(size) > 0:
+		sqlType = fmt.Sprintf("VARCHAR(%v)", size)
+	case sf.Type.Kind() == reflect.String:
+		sqlType = "VARCHAR(191)"
+	case sf.Type.Kind() == reflect.Uint32:
+		sqlType = "INT UNSIGNED"
+	case sf.Type.Kind() == reflect.Uint64:
+		sqlType = "BIGINT UNSIGNED"
+	case sf.Type.Kind() == reflect.Int:
+		sqlType = "INT"
+	case sf.Type.Kind() == reflect.Bool:
+		sqlType = "TINYINT(1)"
+	case sf.Type == reflect.TypeOf(time.Time{}):
+		sqlType = "TIMESTAMP"
+	case sf.Type == reflect.TypeOf([]byte{}):
+		sqlType = "BLOB"
+	default:
+		return field{}, false
+	}
+
+	return field{
+		Name:    name,
+		SQLType: sqlType,
+		Primary: strings.EqualFold(name, "id"),
+	}, true
+}
+
+// mapStruct maps every tagged field of a registered struct.
+func mapStruct(v interface{}) mappedTable {
+	t := reflect.TypeOf(v)
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	mt := mappedTable{Name: tableName(v)}
+	for i := 0; i < t.NumField(); i++ {
+		f, ok := mapField(t.Field(i))
+		if ok {
+			mt.Fields = append(mt.Fields, f)
+		}
+	}
+
+	return mt
+}
+
+// *****************************************************************************
+// Live schema introspection
+// *****************************************************************************
+
+// existingColumn is a row from information_schema.columns.
+type existingColumn struct {
+	Name string `db:"COLUMN_NAME"`
+	Type string `db:"COLUMN_TYPE"`
+}
+
+// existingColumns returns the live columns of tableName, or an empty slice
+// if the table does not exist yet.
+func existingColumns(con *sqlx.DB, schema string, tableName string) ([]existingColumn, error) {
+	var columns []existingColumn
+	err := con.Select(&columns, `SELECT COLUMN_NAME, COLUMN_TYPE FROM information_schema.columns
+		WHERE table_schema = ? AND table_name = ? ORDER BY ORDINAL_POSITION;`, schema, tableName)
+	return columns, err
+}
+
+// *****************************************************************************
+// Diffing
+// *****************************************************************************
+
+// integerWidth matches MySQL's optional display-width suffix on an integer
+// type, e.g. the "(10)" in "int(10)" or "bigint(20) unsigned". TINYINT is
+// excluded: MySQL uses TINYINT(1) to mark a boolean column versus the
+// TINYINT(4) of a plain 8-bit integer, so there the width is meaningful
+// rather than legacy display padding that information_schema still reports
+// pre-8.0.19 even though mapField never generates it.
+var integerWidth = regexp.MustCompile(`(?i)^(int|bigint|smallint|mediumint)\(\d+\)`)
+
+// normalizeType strips a display width information_schema may report but
+// mapField never produces, so the two can be compared for an actual type
+// change rather than a cosmetic difference.
+func normalizeType(t string) string {
+	return integerWidth.ReplaceAllString(t, "$1")
+}
+
+// plan is the set of statements generated for a single mapped table.
+type plan struct {
+	Up      []string
+	Down    []string
+	Dropped []string // columns present in the DB but not in the Go struct
+}
+
+// diff compares a mapped struct against its live columns and returns the
+// statements required to bring the table up to date.
+func diff(mt mappedTable, live []existingColumn) plan {
+	var p plan
+
+	if len(live) == 0 {
+		p.Up = append(p.Up, createTableSQL(mt))
+		p.Down = append(p.Down, fmt.Sprintf("DROP TABLE `%v`;", mt.Name))
+		return p
+	}
+
+	liveByName := make(map[string]existingColumn, len(live))
+	for _, c := range live {
+		liveByName[c.Name] = c
+	}
+
+	wantByName := make(map[string]bool, len(mt.Fields))
+	for _, f := range mt.Fields {
+		wantByName[f.Name] = true
+
+		existing, ok := liveByName[f.Name]
+		switch {
+		case !ok:
+			p.Up = append(p.Up, fmt.Sprintf("ALTER TABLE `%v` ADD COLUMN `%v` %v;", mt.Name, f.Name, f.SQLType))
+			p.Down = append(p.Down, fmt.Sprintf("ALTER TABLE `%v` DROP COLUMN `%v`;", mt.Name, f.Name))
+		case !strings.EqualFold(normalizeType(existing.Type), normalizeType(f.SQLType)):
+			p.Up = append(p.Up, fmt.Sprintf("ALTER TABLE `%v` MODIFY COLUMN `%v` %v;", mt.Name, f.Name, f.SQLType))
+			p.Down = append(p.Down, fmt.Sprintf("ALTER TABLE `%v` MODIFY COLUMN `%v` %v;", mt.Name, f.Name, existing.Type))
+		}
+	}
+
+	for _, c := range live {
+		if !wantByName[c.Name] {
+			p.Dropped = append(p.Dropped, fmt.Sprintf("ALTER TABLE `%v` DROP COLUMN `%v`; -- was %v", mt.Name, c.Name, c.Type))
+		}
+	}
+
+	return p
+}
+
+// createTableSQL builds a CREATE TABLE statement for a table that doesn't
+// exist in the live schema yet.
+func createTableSQL(mt mappedTable) string {
+	lines := make([]string, 0, len(mt.Fields)+1)
+	var primary string
+
+	for _, f := range mt.Fields {
+		if f.Primary {
+			lines = append(lines, fmt.Sprintf("`%v` %v NOT NULL AUTO_INCREMENT", f.Name, f.SQLType))
+			primary = f.Name
+			continue
+		}
+		lines = append(lines, fmt.Sprintf("`%v` %v", f.Name, f.SQLType))
+	}
+
+	if len(primary) > 0 {
+		lines = append(lines, fmt.Sprintf("PRIMARY KEY (`%v`)", primary))
+	}
+
+	return fmt.Sprintf("CREATE TABLE `%v` (\n\t%v\n);", mt.Name, strings.Join(lines, ",\n\t"))
+}
+
+// *****************************************************************************
+// Generation
+// *****************************************************************************
+
+// Generate connects to the configured database, diffs every struct passed
+// to Register against the live schema, and writes the resulting migration
+// into the configured MigrationFolder. It returns the path to the written
+// .up.sql file, or an empty string if there was nothing to do.
+func (c Configuration) Generate() (string, error) {
+	i := c.Info
+
+	con, err := i.Connect(true)
+	if err != nil {
+		return "", err
+	}
+	defer con.Close()
+
+	registeredMutex.Lock()
+	structs := append([]interface{}{}, registered...)
+	registeredMutex.Unlock()
+
+	// Keep output deterministic regardless of registration order
+	tables := make([]mappedTable, len(structs))
+	for idx, v := range structs {
+		tables[idx] = mapStruct(v)
+	}
+	sort.Slice(tables, func(a, b int) bool { return tables[a].Name < tables[b].Name })
+
+	var up, down, manual []string
+	for _, mt := range tables {
+		live, err := existingColumns(con, i.Database, mt.Name)
+		if err != nil {
+			return "", err
+		}
+
+		p := diff(mt, live)
+		up = append(up, p.Up...)
+		down = append(down, p.Down...)
+		manual = append(manual, p.Dropped...)
+	}
+
+	if len(up) == 0 && len(manual) == 0 {
+		return "", nil
+	}
+
+	// No separator between date and time: storage/migration's pairFile
+	// regex requires a 14-digit run.
+	stamp := time.Now().Format("20060102150405")
+
+	var upPath string
+	if len(up) > 0 {
+		upPath = filepath.Join(i.MigrationFolder, fmt.Sprintf("%v_auto.up.sql", stamp))
+		downPath := filepath.Join(i.MigrationFolder, fmt.Sprintf("%v_auto.down.sql", stamp))
+
+		if err := ioutil.WriteFile(upPath, []byte(strings.Join(up, "\n")+"\n"), 0644); err != nil {
+			return "", err
+		}
+
+		// Down statements undo up statements in reverse order
+		reversed := make([]string, len(down))
+		for i, stmt := range down {
+			reversed[len(down)-1-i] = stmt
+		}
+
+		if err := ioutil.WriteFile(downPath, []byte(strings.Join(reversed, "\n")+"\n"), 0644); err != nil {
+			return "", err
+		}
+	}
+
+	if len(manual) > 0 {
+		// Written to a "manual" subfolder, not directly in MigrationFolder,
+		// so storage/migration's pairs() glob (which only looks at
+		// MigrationFolder itself) never sees it and tries to apply it as a
+		// regular timestamped migration.
+		manualDir := filepath.Join(i.MigrationFolder, "manual")
+		if err := os.MkdirAll(manualDir, 0755); err != nil {
+			return "", err
+		}
+
+		manualPath := filepath.Join(manualDir, fmt.Sprintf("%v_auto.manual.sql", stamp))
+		header := "-- Columns present in the database but not in the registered Go structs.\n" +
+			"-- auto never drops data automatically; review and run manually.\n"
+		if err := ioutil.WriteFile(manualPath, []byte(header+strings.Join(manual, "\n")+"\n"), 0644); err != nil {
+			return "", err
+		}
+	}
+
+	return upPath, nil
+}
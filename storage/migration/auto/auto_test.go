@@ -0,0 +1,178 @@
+package auto
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+type UserAccount struct{}
+
+type Widget struct{}
+
+func TestTableName(t *testing.T) {
+	cases := []struct {
+		name string
+		v    interface{}
+		want string
+	}{
+		{"multi-word, pointer", &UserAccount{}, "user_account"},
+		{"single word, value", Widget{}, "widget"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if got := tableName(c.v); got != c.want {
+				t.Errorf("tableName(%T) = %q, want %q", c.v, got, c.want)
+			}
+		})
+	}
+}
+
+// sampleEntity exercises every case mapField recognizes, plus the ones it
+// skips.
+type sampleEntity struct {
+	ID        uint32    `db:"id"`
+	Name      string    `db:"name" size:"64"`
+	Bio       string    `db:"bio" size:"text"`
+	Plain     string    `db:"plain"`
+	Count     uint64    `db:"count"`
+	Num       int       `db:"num"`
+	Active    bool      `db:"active"`
+	CreatedAt time.Time `db:"created_at"`
+	Blob      []byte    `db:"blob"`
+	Untagged  string
+	Skipped   string  `db:"-"`
+	Unmapped  float64 `db:"unmapped"`
+}
+
+func TestMapField(t *testing.T) {
+	typ := reflect.TypeOf(sampleEntity{})
+
+	cases := []struct {
+		field     string
+		wantOK    bool
+		wantField field
+	}{
+		{"ID", true, field{Name: "id", SQLType: "INT UNSIGNED", Primary: true}},
+		{"Name", true, field{Name: "name", SQLType: "VARCHAR(64)", Primary: false}},
+		{"Bio", true, field{Name: "bio", SQLType: "TEXT", Primary: false}},
+		{"Plain", true, field{Name: "plain", SQLType: "VARCHAR(191)", Primary: false}},
+		{"Count", true, field{Name: "count", SQLType: "BIGINT UNSIGNED", Primary: false}},
+		{"Num", true, field{Name: "num", SQLType: "INT", Primary: false}},
+		{"Active", true, field{Name: "active", SQLType: "TINYINT(1)", Primary: false}},
+		{"CreatedAt", true, field{Name: "created_at", SQLType: "TIMESTAMP", Primary: false}},
+		{"Blob", true, field{Name: "blob", SQLType: "BLOB", Primary: false}},
+		{"Untagged", false, field{}},
+		{"Skipped", false, field{}},
+		{"Unmapped", false, field{}},
+	}
+
+	for _, c := range cases {
+		t.Run(c.field, func(t *testing.T) {
+			sf, ok := typ.FieldByName(c.field)
+			if !ok {
+				t.Fatalf("no such field %q on sampleEntity", c.field)
+			}
+
+			got, ok := mapField(sf)
+			if ok != c.wantOK {
+				t.Fatalf("mapField(%v) ok = %v, want %v", c.field, ok, c.wantOK)
+			}
+			if ok && got != c.wantField {
+				t.Errorf("mapField(%v) = %+v, want %+v", c.field, got, c.wantField)
+			}
+		})
+	}
+}
+
+func TestDiff(t *testing.T) {
+	mt := mappedTable{
+		Name: "widgets",
+		Fields: []field{
+			{Name: "id", SQLType: "INT UNSIGNED", Primary: true},
+			{Name: "name", SQLType: "VARCHAR(191)"},
+		},
+	}
+
+	t.Run("table doesn't exist yet", func(t *testing.T) {
+		p := diff(mt, nil)
+		if len(p.Up) != 1 || len(p.Down) != 1 || len(p.Dropped) != 0 {
+			t.Fatalf("diff() = %+v, want a single CREATE/DROP TABLE pair", p)
+		}
+		if p.Up[0] != createTableSQL(mt) {
+			t.Errorf("Up[0] = %q, want %q", p.Up[0], createTableSQL(mt))
+		}
+	})
+
+	t.Run("already up to date", func(t *testing.T) {
+		live := []existingColumn{
+			{Name: "id", Type: "INT UNSIGNED"},
+			{Name: "name", Type: "VARCHAR(191)"},
+		}
+		p := diff(mt, live)
+		if len(p.Up) != 0 || len(p.Down) != 0 || len(p.Dropped) != 0 {
+			t.Errorf("diff() = %+v, want no changes", p)
+		}
+	})
+
+	t.Run("already up to date, pre-8.0.19 display width", func(t *testing.T) {
+		// Every MySQL server before 8.0.19 reports COLUMN_TYPE with a
+		// display width, e.g. "int(10) unsigned" rather than mapField's
+		// width-free "INT UNSIGNED" -- that's cosmetic, not drift.
+		live := []existingColumn{
+			{Name: "id", Type: "int(10) unsigned"},
+			{Name: "name", Type: "VARCHAR(191)"},
+		}
+		p := diff(mt, live)
+		if len(p.Up) != 0 || len(p.Down) != 0 || len(p.Dropped) != 0 {
+			t.Errorf("diff() = %+v, want no changes for a display-width-only difference", p)
+		}
+	})
+
+	t.Run("tinyint(1) boolean column is not re-widened", func(t *testing.T) {
+		boolTable := mappedTable{
+			Name:   "flags",
+			Fields: []field{{Name: "active", SQLType: "TINYINT(1)"}},
+		}
+		live := []existingColumn{{Name: "active", Type: "tinyint(1)"}}
+		p := diff(boolTable, live)
+		if len(p.Up) != 0 || len(p.Down) != 0 {
+			t.Errorf("diff() = %+v, want no changes for a matching TINYINT(1)", p)
+		}
+	})
+
+	t.Run("missing column", func(t *testing.T) {
+		live := []existingColumn{{Name: "id", Type: "INT UNSIGNED"}}
+		p := diff(mt, live)
+		if len(p.Up) != 1 || len(p.Down) != 1 {
+			t.Fatalf("diff() = %+v, want one ADD COLUMN / DROP COLUMN pair", p)
+		}
+	})
+
+	t.Run("changed column type", func(t *testing.T) {
+		live := []existingColumn{
+			{Name: "id", Type: "INT UNSIGNED"},
+			{Name: "name", Type: "VARCHAR(64)"},
+		}
+		p := diff(mt, live)
+		if len(p.Up) != 1 || len(p.Down) != 1 {
+			t.Fatalf("diff() = %+v, want one MODIFY COLUMN pair", p)
+		}
+	})
+
+	t.Run("dropped column is never applied automatically", func(t *testing.T) {
+		live := []existingColumn{
+			{Name: "id", Type: "INT UNSIGNED"},
+			{Name: "name", Type: "VARCHAR(191)"},
+			{Name: "legacy", Type: "TEXT"},
+		}
+		p := diff(mt, live)
+		if len(p.Up) != 0 || len(p.Down) != 0 {
+			t.Errorf("diff() = %+v, want no Up/Down for a dropped column", p)
+		}
+		if len(p.Dropped) != 1 {
+			t.Fatalf("diff() Dropped = %+v, want exactly one entry", p.Dropped)
+		}
+	})
+}
@@ -0,0 +1,294 @@
+// Package migration provides a dialect-agnostic schema migration runner.
+//
+// A concrete Dialect implementation (see storage/migration/mysql,
+// storage/migration/sqlite, and storage/migration/postgres) supplies the
+// driver-specific SQL needed to track and apply migrations; Info drives the
+// file discovery, ordering and checksum verification that is common to
+// every dialect.
+//
+// Migration files follow the "YYYYMMDDHHMMSS_description.up.sql" /
+// ".down.sql" pairing convention: the numeric prefix orders migrations and
+// the stem (prefix + description) is the name recorded in the tracking
+// table.
+package migration
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"regexp"
+	"sort"
+)
+
+// Dialect abstracts the driver-specific parts of running migrations so that
+// Info can stay database-agnostic.
+type Dialect interface {
+	// Extension returns the file extension (with leading period) used by
+	// migration files for this dialect.
+	Extension() string
+
+	// TableExist returns an error if the migration tracking table does not
+	// exist yet.
+	TableExist() error
+
+	// CreateTable creates the migration tracking table, including the
+	// checksum and applied_at columns.
+	CreateTable() error
+
+	// EnsureSchema adds any columns a pre-existing migration table from
+	// before checksum verification was introduced is missing.
+	EnsureSchema() error
+
+	// Status returns the name of the most recently applied migration, or an
+	// empty string if none have been applied.
+	Status() (string, error)
+
+	// AppliedChecksums returns the stored checksum of every applied
+	// migration, keyed by name.
+	AppliedChecksums() (map[string]string, error)
+
+	// Migrate executes a single migration statement.
+	Migrate(qry string) error
+
+	// RecordUp marks a migration as applied, storing the SHA-256 checksum
+	// of its .up.sql file.
+	RecordUp(name string, checksum string) error
+
+	// RecordDown removes the record of a migration being applied.
+	RecordDown(name string) error
+}
+
+// Info drives migrations for a single Dialect.
+type Info struct {
+	dialect Dialect
+	folder  string
+
+	// ForceChecksum skips the checksum comparison that otherwise refuses to
+	// run further migrations when a previously-applied file has changed on
+	// disk.
+	ForceChecksum bool
+}
+
+// New ensures the migration tracking table exists (creating it, or
+// upgrading it with any columns a pre-existing table is missing) and
+// returns an Info ready to apply migrations found in folder.
+func New(dialect Dialect, folder string) (*Info, error) {
+	m := &Info{
+		dialect: dialect,
+		folder:  folder,
+	}
+
+	if err := dialect.TableExist(); err != nil {
+		if err := dialect.CreateTable(); err != nil {
+			return m, err
+		}
+	} else if err := dialect.EnsureSchema(); err != nil {
+		return m, err
+	}
+
+	return m, nil
+}
+
+// pair is a single timestamped migration: its stem is what's recorded in
+// the tracking table, with up/down holding the absolute paths of its two
+// files.
+type pair struct {
+	name string
+	up   string
+	down string
+}
+
+// pairFile matches "<14-digit timestamp>_<description>.(up|down).sql" (or
+// whatever extension the dialect uses).
+var pairFile = regexp.MustCompile(`^(\d{14})_(.+)\.(up|down)$`)
+
+// pairs returns every timestamped migration found in m.folder, sorted by
+// timestamp.
+func (m *Info) pairs() ([]pair, error) {
+	matches, err := filepath.Glob(filepath.Join(m.folder, "*"+m.dialect.Extension()))
+	if err != nil {
+		return nil, err
+	}
+
+	byName := make(map[string]*pair)
+
+	for _, match := range matches {
+		base := filepath.Base(match)
+		stem := base[:len(base)-len(m.dialect.Extension())]
+
+		sub := pairFile.FindStringSubmatch(stem)
+		if sub == nil {
+			return nil, fmt.Errorf("migration: %v doesn't match the YYYYMMDDHHMMSS_description.(up|down)%v convention", base, m.dialect.Extension())
+		}
+
+		name := sub[1] + "_" + sub[2]
+
+		p, ok := byName[name]
+		if !ok {
+			p = &pair{name: name}
+			byName[name] = p
+		}
+
+		if sub[3] == "up" {
+			p.up = match
+		} else {
+			p.down = match
+		}
+	}
+
+	list := make([]pair, 0, len(byName))
+	for _, p := range byName {
+		if len(p.up) == 0 {
+			return nil, fmt.Errorf("migration: %v is missing its .up%v file", p.name, m.dialect.Extension())
+		}
+		list = append(list, *p)
+	}
+
+	sort.Slice(list, func(i, j int) bool { return list[i].name < list[j].name })
+
+	return list, nil
+}
+
+// checksum returns the hex-encoded SHA-256 of the file at path.
+func checksum(path string) (string, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// verifyChecksums refuses to proceed if a migration that has already been
+// applied no longer matches what's on disk, unless ForceChecksum is set.
+func (m *Info) verifyChecksums(pairs []pair) error {
+	if m.ForceChecksum {
+		return nil
+	}
+
+	applied, err := m.dialect.AppliedChecksums()
+	if err != nil {
+		return err
+	}
+
+	for _, p := range pairs {
+		stored, ok := applied[p.name]
+		if !ok || len(stored) == 0 {
+			// Not yet applied, or applied before checksum tracking existed
+			// (EnsureSchema backfills those rows with an empty checksum):
+			// nothing on disk to compare against.
+			continue
+		}
+
+		sum, err := checksum(p.up)
+		if err != nil {
+			return err
+		}
+
+		if sum != stored {
+			return fmt.Errorf("migration: %v has changed since it was applied; pass ForceChecksum to override", p.name)
+		}
+	}
+
+	return nil
+}
+
+// UpAll applies every migration that has not yet run, in order.
+func (m *Info) UpAll() error {
+	pairs, err := m.pairs()
+	if err != nil {
+		return err
+	}
+
+	if err := m.verifyChecksums(pairs); err != nil {
+		return err
+	}
+
+	last, err := m.dialect.Status()
+	if err != nil {
+		return err
+	}
+
+	pending := pairs
+	if len(last) > 0 {
+		for i, p := range pairs {
+			if p.name == last {
+				pending = pairs[i+1:]
+				break
+			}
+		}
+	}
+
+	for _, p := range pending {
+		if err := m.up(p); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// DownAll reverts every applied migration, most recent first.
+func (m *Info) DownAll() error {
+	pairs, err := m.pairs()
+	if err != nil {
+		return err
+	}
+
+	byName := make(map[string]pair, len(pairs))
+	for _, p := range pairs {
+		byName[p.name] = p
+	}
+
+	for {
+		last, err := m.dialect.Status()
+		if err != nil {
+			return err
+		}
+
+		if len(last) == 0 {
+			return nil
+		}
+
+		if err := m.down(last, byName[last]); err != nil {
+			return err
+		}
+	}
+}
+
+// up runs a single migration's .up file and records it, checksum included.
+func (m *Info) up(p pair) error {
+	b, err := ioutil.ReadFile(p.up)
+	if err != nil {
+		return err
+	}
+
+	if err := m.dialect.Migrate(string(b)); err != nil {
+		return fmt.Errorf("migration %v: %w", p.name, err)
+	}
+
+	sum := sha256.Sum256(b)
+	return m.dialect.RecordUp(p.name, hex.EncodeToString(sum[:]))
+}
+
+// down runs a migration's .down file, if it has one, and removes its
+// tracking record. p may be the zero value if the file backing an
+// already-applied migration has since been deleted, in which case only the
+// tracking record is removed.
+func (m *Info) down(name string, p pair) error {
+	if len(p.down) > 0 {
+		b, err := ioutil.ReadFile(p.down)
+		if err != nil {
+			return err
+		}
+
+		if err := m.dialect.Migrate(string(b)); err != nil {
+			return fmt.Errorf("migration %v (down): %w", name, err)
+		}
+	}
+
+	return m.dialect.RecordDown(name)
+}
@@ -0,0 +1,134 @@
+package migration
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+// stubDialect is a minimal Dialect used to drive Info's pure file-discovery
+// and checksum logic without a real database.
+type stubDialect struct {
+	ext     string
+	applied map[string]string
+}
+
+func (s *stubDialect) Extension() string                            { return s.ext }
+func (s *stubDialect) TableExist() error                            { return nil }
+func (s *stubDialect) CreateTable() error                           { return nil }
+func (s *stubDialect) EnsureSchema() error                          { return nil }
+func (s *stubDialect) Status() (string, error)                      { return "", nil }
+func (s *stubDialect) AppliedChecksums() (map[string]string, error) { return s.applied, nil }
+func (s *stubDialect) Migrate(qry string) error                     { return nil }
+func (s *stubDialect) RecordUp(name string, checksum string) error  { return nil }
+func (s *stubDialect) RecordDown(name string) error                 { return nil }
+
+func TestPairFile(t *testing.T) {
+	cases := []struct {
+		name string
+		stem string
+		want bool
+	}{
+		{"valid up", "20260101000000_create_widgets.up", true},
+		{"valid down", "20260101000000_create_widgets.down", true},
+		{"dash breaks the 14-digit run", "20260101-000000_create_widgets.up", false},
+		{"too few digits", "2026010100000_create_widgets.up", false},
+		{"empty description", "20260101000000_.up", false},
+		{"wrong suffix", "20260101000000_create_widgets.manual", false},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if got := pairFile.MatchString(c.stem); got != c.want {
+				t.Errorf("pairFile.MatchString(%q) = %v, want %v", c.stem, got, c.want)
+			}
+		})
+	}
+}
+
+func write(t *testing.T, dir, name, content string) {
+	t.Helper()
+	if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestInfoPairs(t *testing.T) {
+	dir := t.TempDir()
+	write(t, dir, "20260101000000_create_widgets.up.sql", "CREATE TABLE widgets (id INT);")
+	write(t, dir, "20260101000000_create_widgets.down.sql", "DROP TABLE widgets;")
+	write(t, dir, "20260102000000_add_column.up.sql", "ALTER TABLE widgets ADD COLUMN name TEXT;")
+
+	m := &Info{dialect: &stubDialect{ext: ".sql"}, folder: dir}
+
+	pairs, err := m.pairs()
+	if err != nil {
+		t.Fatalf("pairs() error: %v", err)
+	}
+
+	if len(pairs) != 2 {
+		t.Fatalf("len(pairs) = %d, want 2", len(pairs))
+	}
+	if pairs[0].name != "20260101000000_create_widgets" || pairs[1].name != "20260102000000_add_column" {
+		t.Fatalf("pairs out of order: %+v", pairs)
+	}
+	if len(pairs[1].down) != 0 {
+		t.Errorf("a .down file is optional; expected none for %v, got %q", pairs[1].name, pairs[1].down)
+	}
+}
+
+func TestInfoPairsRejectsNonConformingFile(t *testing.T) {
+	dir := t.TempDir()
+	write(t, dir, "20260101-000000_bad.up.sql", "x")
+
+	m := &Info{dialect: &stubDialect{ext: ".sql"}, folder: dir}
+	if _, err := m.pairs(); err == nil {
+		t.Fatal("expected an error for a non-conforming filename, got nil")
+	}
+}
+
+func TestInfoPairsRequiresUpFile(t *testing.T) {
+	dir := t.TempDir()
+	write(t, dir, "20260101000000_create_widgets.down.sql", "DROP TABLE widgets;")
+
+	m := &Info{dialect: &stubDialect{ext: ".sql"}, folder: dir}
+	if _, err := m.pairs(); err == nil {
+		t.Fatal("expected an error for a migration missing its .up file, got nil")
+	}
+}
+
+func TestVerifyChecksums(t *testing.T) {
+	dir := t.TempDir()
+	upPath := filepath.Join(dir, "20260101000000_create_widgets.up.sql")
+	write(t, dir, "20260101000000_create_widgets.up.sql", "CREATE TABLE widgets (id INT);")
+
+	sum, err := checksum(upPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pairs := []pair{{name: "20260101000000_create_widgets", up: upPath}}
+
+	cases := []struct {
+		name          string
+		applied       map[string]string
+		forceChecksum bool
+		wantErr       bool
+	}{
+		{"not yet applied", map[string]string{}, false, false},
+		{"matching checksum", map[string]string{"20260101000000_create_widgets": sum}, false, false},
+		{"legacy row backfilled with an empty checksum", map[string]string{"20260101000000_create_widgets": ""}, false, false},
+		{"changed since it was applied", map[string]string{"20260101000000_create_widgets": "deadbeef"}, false, true},
+		{"changed but ForceChecksum set", map[string]string{"20260101000000_create_widgets": "deadbeef"}, true, false},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			m := &Info{dialect: &stubDialect{applied: c.applied}, ForceChecksum: c.forceChecksum}
+			err := m.verifyChecksums(pairs)
+			if (err != nil) != c.wantErr {
+				t.Errorf("verifyChecksums() error = %v, wantErr %v", err, c.wantErr)
+			}
+		})
+	}
+}
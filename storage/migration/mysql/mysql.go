@@ -2,19 +2,25 @@
 package mysql
 
 import (
+	"bufio"
 	"database/sql"
 	"fmt"
+	"io"
 	"log"
 	"os"
+	"os/exec"
 	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 
+	"github.com/jmoiron/sqlx"
 	"github.com/stefanwuthrich/core/file"
-	"github.com/stefanwuthrich/core/storage"
+	"github.com/stefanwuthrich/core/jsonconfig"
 	database "github.com/stefanwuthrich/core/storage/driver/mysql"
 	"github.com/stefanwuthrich/core/storage/migration"
-	"github.com/jmoiron/sqlx"
 )
 
 // *****************************************************************************
@@ -63,9 +69,42 @@ func Shared() Configuration {
 // Migration Creation
 // *****************************************************************************
 
-var (
-	migrationTable = "migration"
-)
+// defaultMigrationTable is used when database.Info.MigrationTable is left
+// blank, preserving the historical table name.
+const defaultMigrationTable = "migration"
+
+// tableNamePart matches a single unquoted identifier, either a bare table
+// name or one half of a schema-qualified "schema.table" name.
+var tableNamePart = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// quoteTableName validates and backtick-quotes a user-supplied migration
+// table name, rejecting anything that isn't a plain identifier (optionally
+// schema-qualified) before it is interpolated into SQL. It also returns the
+// bare table name (without any schema qualifier or quoting), which is what
+// information_schema queries expect.
+func quoteTableName(name string) (quoted string, schema string, table string, err error) {
+	parts := strings.Split(name, ".")
+	if len(parts) > 2 {
+		return "", "", "", fmt.Errorf("mysql: invalid migration table name %q", name)
+	}
+
+	for _, part := range parts {
+		if !tableNamePart.MatchString(part) {
+			return "", "", "", fmt.Errorf("mysql: invalid migration table name %q", name)
+		}
+	}
+
+	quotedParts := make([]string, len(parts))
+	for i, part := range parts {
+		quotedParts[i] = fmt.Sprintf("`%v`", part)
+	}
+
+	if len(parts) == 2 {
+		schema = parts[0]
+	}
+
+	return strings.Join(quotedParts, "."), schema, parts[len(parts)-1], nil
+}
 
 // New creates a migration connection to the database.
 func (c Configuration) New() (*migration.Info, error) {
@@ -91,6 +130,18 @@ func (c Configuration) New() (*migration.Info, error) {
 	// Update the config
 	mi.UpdateConfig(&i)
 
+	// Validate and quote the configured migration table name
+	table, rawSchema, rawTable, err := quoteTableName(i.MigrationTable)
+	if err != nil {
+		return mig, err
+	}
+	mi.table = table
+	mi.rawSchema = rawSchema
+	mi.rawTable = rawTable
+
+	// Keep the full config around so Migrate can reach the gh-ost settings
+	mi.ghost = i
+
 	// Connect to the database
 	con, err := i.Connect(true)
 
@@ -124,15 +175,44 @@ func (c Configuration) New() (*migration.Info, error) {
 
 	// Store the connection in the entity
 	mi.sql = con
+	mi.charset = i.Charset
+	mi.collation = i.Collation
+
+	// Figure out whether the server is MySQL 8+ so CreateTable can drop the
+	// utf8mb3 VARCHAR(191) workaround for the 767-byte index limit.
+	mi.serverVersion = i.ServerVersion
+	if len(mi.serverVersion) == 0 {
+		// Best-effort: an older/locked-down server that can't run this is
+		// simply treated as pre-8.0.
+		_ = con.Get(&mi.serverVersion, "SELECT VERSION();")
+	}
 
 	// Setup logic was here
 	return migration.New(mi, folder)
 }
 
+// mysql8OrNewer matches a leading major version number of 8 or higher in a
+// "SELECT VERSION()" style string (e.g. "8.0.34", "8.0.34-log").
+var mysql8OrNewer = regexp.MustCompile(`^([0-9]+)\.`)
+
+// isMySQL8Plus reports whether version looks like MySQL 8.0 or newer.
+func isMySQL8Plus(version string) bool {
+	m := mysql8OrNewer.FindStringSubmatch(version)
+	if m == nil {
+		return false
+	}
+
+	major, err := strconv.Atoi(m[1])
+	return err == nil && major >= 8
+}
+
 // *****************************************************************************
 // Interface
 // *****************************************************************************
 
+// Entity implements migration.Dialect for MySQL.
+var _ migration.Dialect = (*Entity)(nil)
+
 // Extension returns the file extension with a period
 func (t *Entity) Extension() string {
 	return ".sql"
@@ -141,11 +221,23 @@ func (t *Entity) Extension() string {
 // UpdateConfig will update any parameters necessary
 func (t *Entity) UpdateConfig(config *database.Info) {
 	config.Parameter = "parseTime=true&multiStatements=true"
+
+	if len(config.MigrationTable) == 0 {
+		config.MigrationTable = defaultMigrationTable
+	}
+
+	if len(config.Charset) == 0 {
+		config.Charset = "utf8mb4"
+	}
+
+	if len(config.Collation) == 0 {
+		config.Collation = "utf8mb4_unicode_ci"
+	}
 }
 
 // TableExist returns true if the migration table exists
 func (t *Entity) TableExist() error {
-	_, err := t.sql.Exec(fmt.Sprintf("SELECT 1 FROM %v LIMIT 1;", migrationTable))
+	_, err := t.sql.Exec(fmt.Sprintf("SELECT 1 FROM %v LIMIT 1;", t.table))
 	if err != nil {
 		return err
 	}
@@ -153,15 +245,36 @@ func (t *Entity) TableExist() error {
 	return err
 }
 
-// CreateTable returns true if the migration was created
+// CreateTable returns true if the migration was created.
+//
+// On MySQL 8+, the VARCHAR(191) workaround for the 767-byte utf8mb3 index
+// limit is no longer needed (innodb_large_prefix/DYNAMIC row format are the
+// default), so the table is created wider and with an explicit utf8mb4
+// charset and collation instead.
 func (t *Entity) CreateTable() error {
+	if isMySQL8Plus(t.serverVersion) {
+		_, err := t.sql.Exec(fmt.Sprintf(`CREATE TABLE %v (
+			id INT UNSIGNED NOT NULL AUTO_INCREMENT,
+			name VARCHAR(255) NOT NULL,
+			checksum CHAR(64) NOT NULL DEFAULT '',
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			applied_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			UNIQUE KEY (name),
+			PRIMARY KEY (id)
+			) ENGINE=InnoDB DEFAULT CHARSET=%v COLLATE=%v;`, t.table, t.charset, t.collation))
+
+		return err
+	}
+
 	_, err := t.sql.Exec(fmt.Sprintf(`CREATE TABLE %v (
 		id INT UNSIGNED NOT NULL AUTO_INCREMENT,
   		name VARCHAR(191) NOT NULL,
+		checksum CHAR(64) NOT NULL DEFAULT '',
 		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		applied_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
 		UNIQUE KEY (name),
   		PRIMARY KEY (id)
-		);`, migrationTable))
+		);`, t.table))
 
 	if err != nil {
 		return err
@@ -170,10 +283,40 @@ func (t *Entity) CreateTable() error {
 	return err
 }
 
+// EnsureSchema adds the checksum and applied_at columns to a migration
+// table that was created before checksum verification existed.
+func (t *Entity) EnsureSchema() error {
+	for _, col := range []struct {
+		name string
+		ddl  string
+	}{
+		{"checksum", "ADD COLUMN checksum CHAR(64) NOT NULL DEFAULT ''"},
+		{"applied_at", "ADD COLUMN applied_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP"},
+	} {
+		var count int
+		err := t.sql.Get(&count, `SELECT COUNT(*) FROM information_schema.columns
+			WHERE table_schema = COALESCE(NULLIF(?, ''), DATABASE()) AND table_name = ? AND column_name = ?;`,
+			t.rawSchema, t.rawTable, col.name)
+		if err != nil {
+			return err
+		}
+
+		if count > 0 {
+			continue
+		}
+
+		if _, err := t.sql.Exec(fmt.Sprintf("ALTER TABLE %v %v;", t.table, col.ddl)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
 // Status returns last migration name
 func (t *Entity) Status() (string, error) {
 	result := &Entity{}
-	err := t.sql.Get(result, fmt.Sprintf("SELECT * FROM %v ORDER BY id DESC LIMIT 1;", migrationTable))
+	err := t.sql.Get(result, fmt.Sprintf("SELECT * FROM %v ORDER BY id DESC LIMIT 1;", t.table))
 
 	// If no rows, then set to nil
 	if err == sql.ErrNoRows {
@@ -186,25 +329,236 @@ func (t *Entity) Status() (string, error) {
 // statusID returns last migration ID
 func (t *Entity) statusID() (uint32, error) {
 	result := &Entity{}
-	err := t.sql.Get(result, fmt.Sprintf("SELECT * FROM %v ORDER BY id DESC LIMIT 1;", migrationTable))
+	err := t.sql.Get(result, fmt.Sprintf("SELECT * FROM %v ORDER BY id DESC LIMIT 1;", t.table))
 	return result.ID, err
 }
 
-// Migrate runs a query and returns error
+// AppliedChecksums returns the stored checksum of every applied migration,
+// keyed by name.
+func (t *Entity) AppliedChecksums() (map[string]string, error) {
+	var rows []Entity
+	err := t.sql.Select(&rows, fmt.Sprintf("SELECT name, checksum FROM %v;", t.table))
+	if err != nil {
+		return nil, err
+	}
+
+	checksums := make(map[string]string, len(rows))
+	for _, row := range rows {
+		checksums[row.Name] = row.Checksum
+	}
+
+	return checksums, nil
+}
+
+// onlineAnnotation marks a statement that should run through gh-ost instead
+// of a direct ALTER TABLE, e.g. "-- +migrate online table=users".
+var onlineAnnotation = regexp.MustCompile(`(?m)^\s*--\s*\+migrate\s+online\s+table=(\S+)\s*$`)
+
+// alterBody pulls the part of an ALTER TABLE statement that follows the
+// table name, which is what gh-ost expects for --alter.
+var alterBody = regexp.MustCompile("(?is)^ALTER\\s+TABLE\\s+`?[A-Za-z0-9_]+`?\\s+(.*?);?\\s*$")
+
+// Migrate splits qry into individual statements and runs each one in turn.
+// A statement preceded by a "-- +migrate online table=<name>" comment is
+// routed through gh-ost instead of the normal connection, so a single file
+// can mix online DDL with regular DML.
 func (t *Entity) Migrate(qry string) error {
-	_, err := t.sql.Exec(qry)
-	return err
+	for _, stmt := range splitStatements(qry) {
+		if m := onlineAnnotation.FindStringSubmatch(stmt); m != nil {
+			table := m[1]
+			body := onlineAnnotation.ReplaceAllString(stmt, "")
+
+			alter := alterBody.FindStringSubmatch(strings.TrimSpace(body))
+			if alter == nil {
+				return fmt.Errorf("mysql: %q is annotated for an online migration but isn't a single ALTER TABLE statement", table)
+			}
+
+			if err := t.migrateOnline(table, alter[1]); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if _, err := t.sql.Exec(stmt); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// splitStatements breaks a migration file's contents into individual
+// semicolon-terminated statements, keeping any leading comment lines (such
+// as the online-migration annotation) attached to the statement they
+// precede.
+//
+// It tracks single/double/backtick-quoted sections and "--"/"#"/"/* */"
+// comments so a semicolon inside a string literal (e.g. a
+// "VALUES ('a;b')" backfill) doesn't split a statement in two.
+func splitStatements(qry string) []string {
+	var statements []string
+
+	var current strings.Builder
+	runes := []rune(qry)
+	n := len(runes)
+
+	flush := func() {
+		stmt := strings.TrimSpace(current.String())
+		if len(stmt) > 0 {
+			statements = append(statements, stmt+";")
+		}
+		current.Reset()
+	}
+
+	for i := 0; i < n; {
+		r := runes[i]
+
+		switch {
+		case r == '\'' || r == '"' || r == '`':
+			quote := r
+			current.WriteRune(r)
+			i++
+
+			for i < n {
+				c := runes[i]
+
+				// Backslash escapes the next rune (MySQL's default
+				// NO_BACKSLASH_ESCAPES=off behavior); doesn't apply inside
+				// backtick-quoted identifiers.
+				if c == '\\' && quote != '`' && i+1 < n {
+					current.WriteRune(c)
+					current.WriteRune(runes[i+1])
+					i += 2
+					continue
+				}
+
+				current.WriteRune(c)
+				i++
+
+				if c == quote {
+					// A doubled quote ('' inside '...') escapes to a
+					// literal quote rather than closing the string.
+					if i < n && runes[i] == quote {
+						current.WriteRune(runes[i])
+						i++
+						continue
+					}
+					break
+				}
+			}
+
+		case r == '-' && i+1 < n && runes[i+1] == '-', r == '#':
+			for i < n && runes[i] != '\n' {
+				current.WriteRune(runes[i])
+				i++
+			}
+
+		case r == '/' && i+1 < n && runes[i+1] == '*':
+			current.WriteRune(r)
+			current.WriteRune(runes[i+1])
+			i += 2
+
+			for i < n && !(runes[i] == '*' && i+1 < n && runes[i+1] == '/') {
+				current.WriteRune(runes[i])
+				i++
+			}
+
+			if i < n {
+				current.WriteRune(runes[i])
+				current.WriteRune(runes[i+1])
+				i += 2
+			}
+
+		case r == ';':
+			flush()
+			i++
+
+		default:
+			current.WriteRune(r)
+			i++
+		}
+	}
+
+	flush()
+
+	return statements
+}
+
+// migrateOnline runs an ALTER TABLE against table via gh-ost instead of the
+// normal connection, streaming its output to the log so an operator can
+// follow progress on a long-running online migration.
+func (t *Entity) migrateOnline(table string, alter string) error {
+	binary := t.ghost.GhostBinary
+	if len(binary) == 0 {
+		binary = "gh-ost"
+	}
+
+	args := []string{
+		fmt.Sprintf("--host=%v", t.ghost.Host),
+		fmt.Sprintf("--port=%v", t.ghost.Port),
+		fmt.Sprintf("--user=%v", t.ghost.Username),
+		fmt.Sprintf("--password=%v", t.ghost.Password),
+		fmt.Sprintf("--database=%v", t.ghost.Database),
+		fmt.Sprintf("--table=%v", table),
+		fmt.Sprintf("--alter=%v", alter),
+		"--execute",
+	}
+
+	if t.ghost.GhostChunkSize > 0 {
+		args = append(args, fmt.Sprintf("--chunk-size=%v", t.ghost.GhostChunkSize))
+	}
+	if len(t.ghost.GhostMaxLoad) > 0 {
+		args = append(args, fmt.Sprintf("--max-load=%v", t.ghost.GhostMaxLoad))
+	}
+	if len(t.ghost.GhostReplicaHost) > 0 {
+		args = append(args, fmt.Sprintf("--assume-master-host=%v", t.ghost.GhostReplicaHost))
+	}
+
+	cmd := exec.Command(binary, args...)
+
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return err
+	}
+
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go streamToLog(&wg, "gh-ost", stdout)
+	go streamToLog(&wg, "gh-ost", stderr)
+	wg.Wait()
+
+	return cmd.Wait()
 }
 
-// RecordUp adds a record to the database
-func (t *Entity) RecordUp(name string) error {
-	_, err := t.sql.Exec(fmt.Sprintf("INSERT INTO %v (name) VALUES (?);", migrationTable), name)
+// streamToLog copies lines from r to the standard logger, prefixed with tag,
+// until r is exhausted.
+func streamToLog(wg *sync.WaitGroup, tag string, r io.Reader) {
+	defer wg.Done()
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		log.Printf("[%v] %v", tag, scanner.Text())
+	}
+}
+
+// RecordUp adds a record to the database, storing the SHA-256 checksum of
+// the migration's .up.sql file so future runs can detect edits to it.
+func (t *Entity) RecordUp(name string, checksum string) error {
+	_, err := t.sql.Exec(fmt.Sprintf("INSERT INTO %v (name, checksum) VALUES (?, ?);", t.table), name, checksum)
 	return err
 }
 
 // RecordDown removes a record from the database and updates the AUTO_INCREMENT value
 func (t *Entity) RecordDown(name string) error {
-	_, err := t.sql.Exec(fmt.Sprintf("DELETE FROM %v WHERE name = ? LIMIT 1;", migrationTable), name)
+	_, err := t.sql.Exec(fmt.Sprintf("DELETE FROM %v WHERE name = ? LIMIT 1;", t.table), name)
 
 	// If the record was removed successfully
 	if err == nil {
@@ -223,7 +577,7 @@ func (t *Entity) RecordDown(name string) error {
 			nextID = ID
 		}
 
-		_, err = t.sql.Exec(fmt.Sprintf("ALTER TABLE %v AUTO_INCREMENT = %v;", migrationTable, nextID))
+		_, err = t.sql.Exec(fmt.Sprintf("ALTER TABLE %v AUTO_INCREMENT = %v;", t.table, nextID))
 	}
 	return err
 }
@@ -232,14 +586,47 @@ func (t *Entity) RecordDown(name string) error {
 type Entity struct {
 	ID        uint32    `db:"id"`
 	Name      string    `db:"name"`
+	Checksum  string    `db:"checksum"`
 	CreatedAt time.Time `db:"created_at"`
+	AppliedAt time.Time `db:"applied_at"`
 	sql       *sqlx.DB
+
+	// table is the quoted, possibly schema-qualified name of the migration
+	// tracking table, as resolved from database.Info.MigrationTable.
+	table string
+
+	// rawTable is the bare table name, without quoting or schema
+	// qualifier, as used in information_schema lookups.
+	rawTable string
+
+	// rawSchema is the schema part of MigrationTable, unquoted, or empty
+	// when MigrationTable isn't schema-qualified (in which case
+	// information_schema lookups fall back to DATABASE()).
+	rawSchema string
+
+	// ghost holds the connection and gh-ost settings needed to run an
+	// annotated online migration.
+	ghost database.Info
+
+	// charset, collation and serverVersion drive the MySQL 8 vs. legacy DDL
+	// branch in CreateTable.
+	charset       string
+	collation     string
+	serverVersion string
 }
 
 // *****************************************************************************
 // Test Helpers
 // *****************************************************************************
 
+// envConfig mirrors the MySQL section of storage.Info. It is kept local to
+// this file (rather than depending on the storage package) so that the test
+// helpers below don't introduce an import cycle with storage, which depends
+// on this package to dispatch Configuration.New().
+type envConfig struct {
+	MySQL database.Info `json:"MySQL"`
+}
+
 // SetUp is a function for unit tests on a separate database.
 func SetUp(envPath string, dbName string) (*migration.Info, Configuration) {
 	// Get the environment variable
@@ -255,7 +642,8 @@ func SetUp(envPath string, dbName string) (*migration.Info, Configuration) {
 	}
 
 	// Load the config
-	config, err := storage.LoadConfig(os.Getenv("JAYCONFIG"))
+	config := &envConfig{}
+	err := jsonconfig.Load(os.Getenv("JAYCONFIG"), config)
 	if err != nil {
 		log.Fatalf("%v", err)
 	}
@@ -0,0 +1,86 @@
+package mysql
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSplitStatements(t *testing.T) {
+	cases := []struct {
+		name string
+		qry  string
+		want []string
+	}{
+		{
+			"simple",
+			"CREATE TABLE t (id INT);",
+			[]string{"CREATE TABLE t (id INT);"},
+		},
+		{
+			"multiple statements",
+			"CREATE TABLE t (id INT);\nALTER TABLE t ADD COLUMN name TEXT;",
+			[]string{"CREATE TABLE t (id INT);", "ALTER TABLE t ADD COLUMN name TEXT;"},
+		},
+		{
+			"semicolon inside single-quoted literal",
+			"INSERT INTO t (name) VALUES ('a;b');",
+			[]string{"INSERT INTO t (name) VALUES ('a;b');"},
+		},
+		{
+			"doubled-quote escape",
+			"INSERT INTO t (name) VALUES ('it''s; fine');",
+			[]string{"INSERT INTO t (name) VALUES ('it''s; fine');"},
+		},
+		{
+			"backslash escape",
+			`UPDATE t SET name = 'x\'; y' WHERE 1=1;`,
+			[]string{`UPDATE t SET name = 'x\'; y' WHERE 1=1;`},
+		},
+		{
+			"line comment keeps annotation attached to its statement",
+			"-- +migrate online table=widgets\nALTER TABLE widgets ADD COLUMN big TEXT;",
+			[]string{"-- +migrate online table=widgets\nALTER TABLE widgets ADD COLUMN big TEXT;"},
+		},
+		{
+			"semicolon inside block comment",
+			"/* note; about this */\nALTER TABLE t ADD COLUMN c INT;",
+			[]string{"/* note; about this */\nALTER TABLE t ADD COLUMN c INT;"},
+		},
+		{
+			"blank input",
+			"   \n  ",
+			nil,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got := splitStatements(c.qry)
+			if !reflect.DeepEqual(got, c.want) {
+				t.Errorf("splitStatements(%q) = %#v, want %#v", c.qry, got, c.want)
+			}
+		})
+	}
+}
+
+func TestOnlineAnnotationAndAlterBody(t *testing.T) {
+	stmt := "-- +migrate online table=widgets\nALTER TABLE `widgets` ADD COLUMN big TEXT;"
+
+	m := onlineAnnotation.FindStringSubmatch(stmt)
+	if m == nil || m[1] != "widgets" {
+		t.Fatalf("onlineAnnotation.FindStringSubmatch(%q) = %v, want table=widgets", stmt, m)
+	}
+
+	body := onlineAnnotation.ReplaceAllString(stmt, "")
+	alter := alterBody.FindStringSubmatch(strings.TrimSpace(body))
+	if alter == nil || alter[1] != "ADD COLUMN big TEXT" {
+		t.Fatalf("alterBody.FindStringSubmatch(%q) = %v, want ADD COLUMN big TEXT", body, alter)
+	}
+}
+
+func TestAlterBodyRejectsNonAlterStatement(t *testing.T) {
+	if alterBody.FindStringSubmatch("INSERT INTO widgets (id) VALUES (1);") != nil {
+		t.Fatal("alterBody matched a non-ALTER statement")
+	}
+}
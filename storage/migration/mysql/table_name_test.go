@@ -0,0 +1,39 @@
+package mysql
+
+import "testing"
+
+func TestQuoteTableName(t *testing.T) {
+	cases := []struct {
+		name       string
+		in         string
+		wantQuoted string
+		wantSchema string
+		wantTable  string
+		wantErr    bool
+	}{
+		{"bare table", "migration", "`migration`", "", "migration", false},
+		{"schema qualified", "app1.migration", "`app1`.`migration`", "app1", "migration", false},
+		{"underscore prefix", "_migrations", "`_migrations`", "", "_migrations", false},
+		{"empty", "", "", "", "", true},
+		{"leading digit", "1migration", "", "", "", true},
+		{"invalid characters", "migration; DROP TABLE x", "", "", "", true},
+		{"too many parts", "a.b.c", "", "", "", true},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			quoted, schema, table, err := quoteTableName(c.in)
+			if (err != nil) != c.wantErr {
+				t.Fatalf("quoteTableName(%q) error = %v, wantErr %v", c.in, err, c.wantErr)
+			}
+			if err != nil {
+				return
+			}
+
+			if quoted != c.wantQuoted || schema != c.wantSchema || table != c.wantTable {
+				t.Errorf("quoteTableName(%q) = (%q, %q, %q), want (%q, %q, %q)",
+					c.in, quoted, schema, table, c.wantQuoted, c.wantSchema, c.wantTable)
+			}
+		})
+	}
+}
@@ -0,0 +1,27 @@
+package mysql
+
+import "testing"
+
+func TestIsMySQL8Plus(t *testing.T) {
+	cases := []struct {
+		version string
+		want    bool
+	}{
+		{"8.0.34", true},
+		{"8.0.34-log", true},
+		{"9.1.0", true},
+		{"5.7.44", false},
+		{"5.7.44-log", false},
+		{"10.11.6-MariaDB", true},
+		{"", false},
+		{"not a version", false},
+	}
+
+	for _, c := range cases {
+		t.Run(c.version, func(t *testing.T) {
+			if got := isMySQL8Plus(c.version); got != c.want {
+				t.Errorf("isMySQL8Plus(%q) = %v, want %v", c.version, got, c.want)
+			}
+		})
+	}
+}
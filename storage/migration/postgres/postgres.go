@@ -0,0 +1,248 @@
+// Package postgres implements PostgreSQL migrations.
+package postgres
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/stefanwuthrich/core/file"
+	database "github.com/stefanwuthrich/core/storage/driver/postgres"
+	"github.com/stefanwuthrich/core/storage/migration"
+)
+
+// *****************************************************************************
+// Thread-Safe Configuration
+// *****************************************************************************
+
+var (
+	info      database.Info
+	infoMutex sync.RWMutex
+)
+
+// SetConfig stores the config.
+func SetConfig(i database.Info) {
+	infoMutex.Lock()
+	info = i
+	infoMutex.Unlock()
+}
+
+// ResetConfig removes the config.
+func ResetConfig() {
+	infoMutex.Lock()
+	info = database.Info{}
+	infoMutex.Unlock()
+}
+
+// Config returns the config.
+func Config() database.Info {
+	infoMutex.RLock()
+	defer infoMutex.RUnlock()
+	return info
+}
+
+// Configuration defines the shared configuration interface.
+type Configuration struct {
+	database.Info
+}
+
+// Shared returns the global configuration information.
+func Shared() Configuration {
+	return Configuration{
+		Config(),
+	}
+}
+
+// *****************************************************************************
+// Migration Creation
+// *****************************************************************************
+
+var (
+	migrationTable = "migration"
+)
+
+// New creates a migration connection to the database.
+func (c Configuration) New() (*migration.Info, error) {
+	var mig *migration.Info
+
+	// Load the config
+	i := c.Info
+
+	// Build the path to the postgres migration folder
+	projectRoot := filepath.Dir(os.Getenv("JAYCONFIG"))
+	folder := filepath.Join(projectRoot, i.MigrationFolder)
+
+	// If the folder doesn't exist
+	if !file.Exists(folder) {
+		// Set to the current folder
+		dir, _ := os.Getwd()
+		folder = filepath.Join(dir, i.MigrationFolder)
+	}
+
+	// Create PostgreSQL entity
+	mi := &Entity{}
+
+	// Connect to the database
+	con, err := i.Connect(true)
+
+	// If the database doesn't exist or can't connect
+	if err != nil {
+		// Close the open connection
+		con.Close()
+
+		// Connect without a database
+		con, err = i.Connect(false)
+		if err != nil {
+			return mig, err
+		}
+
+		// Create the database
+		err = i.Create(con)
+		if err != nil {
+			return mig, err
+		}
+
+		// Close connection
+		con.Close()
+
+		// Reconnect to the database
+		con, err = i.Connect(true)
+		if err != nil {
+			return mig, err
+		}
+	}
+
+	// Store the connection in the entity
+	mi.sql = con
+
+	return migration.New(mi, folder)
+}
+
+// *****************************************************************************
+// Interface
+// *****************************************************************************
+
+// Entity implements migration.Dialect for PostgreSQL.
+var _ migration.Dialect = (*Entity)(nil)
+
+// Extension returns the file extension with a period
+func (t *Entity) Extension() string {
+	return ".sql"
+}
+
+// TableExist returns true if the migration table exists
+func (t *Entity) TableExist() error {
+	_, err := t.sql.Exec(fmt.Sprintf("SELECT 1 FROM %v LIMIT 1;", migrationTable))
+	return err
+}
+
+// CreateTable returns true if the migration table was created
+func (t *Entity) CreateTable() error {
+	_, err := t.sql.Exec(fmt.Sprintf(`CREATE TABLE %v (
+		id SERIAL PRIMARY KEY,
+  		name VARCHAR(191) NOT NULL UNIQUE,
+		checksum CHAR(64) NOT NULL DEFAULT '',
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		applied_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		);`, migrationTable))
+
+	return err
+}
+
+// EnsureSchema adds the checksum and applied_at columns to a migration
+// table that was created before checksum verification existed.
+func (t *Entity) EnsureSchema() error {
+	for _, col := range []struct{ name, ddl string }{
+		{"checksum", "ADD COLUMN checksum CHAR(64) NOT NULL DEFAULT ''"},
+		{"applied_at", "ADD COLUMN applied_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP"},
+	} {
+		var count int
+		err := t.sql.Get(&count, `SELECT COUNT(*) FROM information_schema.columns
+			WHERE table_name = $1 AND column_name = $2;`, migrationTable, col.name)
+		if err != nil {
+			return err
+		}
+
+		if count > 0 {
+			continue
+		}
+
+		if _, err := t.sql.Exec(fmt.Sprintf("ALTER TABLE %v %v;", migrationTable, col.ddl)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Status returns last migration name
+func (t *Entity) Status() (string, error) {
+	result := &Entity{}
+	err := t.sql.Get(result, fmt.Sprintf("SELECT * FROM %v ORDER BY id DESC LIMIT 1;", migrationTable))
+
+	// If no rows, then set to nil
+	if err == sql.ErrNoRows {
+		err = nil
+	}
+
+	return result.Name, err
+}
+
+// AppliedChecksums returns the stored checksum of every applied migration,
+// keyed by name.
+func (t *Entity) AppliedChecksums() (map[string]string, error) {
+	var rows []Entity
+	err := t.sql.Select(&rows, fmt.Sprintf("SELECT name, checksum FROM %v;", migrationTable))
+	if err != nil {
+		return nil, err
+	}
+
+	checksums := make(map[string]string, len(rows))
+	for _, row := range rows {
+		checksums[row.Name] = row.Checksum
+	}
+
+	return checksums, nil
+}
+
+// Migrate runs a query and returns error
+func (t *Entity) Migrate(qry string) error {
+	_, err := t.sql.Exec(qry)
+	return err
+}
+
+// RecordUp adds a record to the database, storing the SHA-256 checksum of
+// the migration's .up.sql file so future runs can detect edits to it.
+func (t *Entity) RecordUp(name string, checksum string) error {
+	_, err := t.sql.Exec(fmt.Sprintf("INSERT INTO %v (name, checksum) VALUES ($1, $2);", migrationTable), name, checksum)
+	return err
+}
+
+// RecordDown removes a record from the database and rewinds the backing
+// sequence so the next insert reuses the freed id, matching MySQL's
+// AUTO_INCREMENT rewind behavior.
+func (t *Entity) RecordDown(name string) error {
+	_, err := t.sql.Exec(fmt.Sprintf("DELETE FROM %v WHERE name = $1;", migrationTable), name)
+	if err != nil {
+		return err
+	}
+
+	_, err = t.sql.Exec(fmt.Sprintf(
+		"SELECT setval(pg_get_serial_sequence('%v', 'id'), COALESCE((SELECT MAX(id) FROM %v), 1));",
+		migrationTable, migrationTable))
+	return err
+}
+
+// Entity defines the migration table
+type Entity struct {
+	ID        uint32    `db:"id"`
+	Name      string    `db:"name"`
+	Checksum  string    `db:"checksum"`
+	CreatedAt time.Time `db:"created_at"`
+	AppliedAt time.Time `db:"applied_at"`
+	sql       *sqlx.DB
+}
@@ -0,0 +1,230 @@
+// Package sqlite implements SQLite migrations.
+package sqlite
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/stefanwuthrich/core/file"
+	database "github.com/stefanwuthrich/core/storage/driver/sqlite"
+	"github.com/stefanwuthrich/core/storage/migration"
+)
+
+// *****************************************************************************
+// Thread-Safe Configuration
+// *****************************************************************************
+
+var (
+	info      database.Info
+	infoMutex sync.RWMutex
+)
+
+// SetConfig stores the config.
+func SetConfig(i database.Info) {
+	infoMutex.Lock()
+	info = i
+	infoMutex.Unlock()
+}
+
+// ResetConfig removes the config.
+func ResetConfig() {
+	infoMutex.Lock()
+	info = database.Info{}
+	infoMutex.Unlock()
+}
+
+// Config returns the config.
+func Config() database.Info {
+	infoMutex.RLock()
+	defer infoMutex.RUnlock()
+	return info
+}
+
+// Configuration defines the shared configuration interface.
+type Configuration struct {
+	database.Info
+}
+
+// Shared returns the global configuration information.
+func Shared() Configuration {
+	return Configuration{
+		Config(),
+	}
+}
+
+// *****************************************************************************
+// Migration Creation
+// *****************************************************************************
+
+var (
+	migrationTable = "migration"
+)
+
+// New creates a migration connection to the database.
+func (c Configuration) New() (*migration.Info, error) {
+	var mig *migration.Info
+
+	// Load the config
+	i := c.Info
+
+	// Build the path to the sqlite migration folder
+	projectRoot := filepath.Dir(os.Getenv("JAYCONFIG"))
+	folder := filepath.Join(projectRoot, i.MigrationFolder)
+
+	// If the folder doesn't exist
+	if !file.Exists(folder) {
+		// Set to the current folder
+		dir, _ := os.Getwd()
+		folder = filepath.Join(dir, i.MigrationFolder)
+	}
+
+	// Create SQLite entity
+	mi := &Entity{}
+
+	// Connect to the database file (it is created automatically if missing)
+	con, err := i.Connect()
+	if err != nil {
+		return mig, err
+	}
+
+	// Store the connection in the entity
+	mi.sql = con
+
+	return migration.New(mi, folder)
+}
+
+// *****************************************************************************
+// Interface
+// *****************************************************************************
+
+// Entity implements migration.Dialect for SQLite.
+var _ migration.Dialect = (*Entity)(nil)
+
+// Extension returns the file extension with a period
+func (t *Entity) Extension() string {
+	return ".sql"
+}
+
+// TableExist returns true if the migration table exists
+func (t *Entity) TableExist() error {
+	_, err := t.sql.Exec(fmt.Sprintf("SELECT 1 FROM %v LIMIT 1;", migrationTable))
+	return err
+}
+
+// CreateTable returns true if the migration table was created
+func (t *Entity) CreateTable() error {
+	_, err := t.sql.Exec(fmt.Sprintf(`CREATE TABLE %v (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+  		name VARCHAR(191) NOT NULL UNIQUE,
+		checksum CHAR(64) NOT NULL DEFAULT '',
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		applied_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		);`, migrationTable))
+
+	return err
+}
+
+// EnsureSchema adds the checksum and applied_at columns to a migration
+// table that was created before checksum verification existed.
+func (t *Entity) EnsureSchema() error {
+	var columns []struct {
+		CID       int            `db:"cid"`
+		Name      string         `db:"name"`
+		Type      string         `db:"type"`
+		NotNull   int            `db:"notnull"`
+		DfltValue sql.NullString `db:"dflt_value"`
+		PK        int            `db:"pk"`
+	}
+	if err := t.sql.Select(&columns, fmt.Sprintf("PRAGMA table_info(%v);", migrationTable)); err != nil {
+		return err
+	}
+
+	have := make(map[string]bool, len(columns))
+	for _, c := range columns {
+		have[c.Name] = true
+	}
+
+	if !have["checksum"] {
+		if _, err := t.sql.Exec(fmt.Sprintf("ALTER TABLE %v ADD COLUMN checksum CHAR(64) NOT NULL DEFAULT '';", migrationTable)); err != nil {
+			return err
+		}
+	}
+
+	if !have["applied_at"] {
+		// SQLite's ALTER TABLE ADD COLUMN rejects the non-constant
+		// CURRENT_TIMESTAMP default that CreateTable uses, so rows that
+		// predate this column are simply left with a NULL applied_at.
+		if _, err := t.sql.Exec(fmt.Sprintf("ALTER TABLE %v ADD COLUMN applied_at TIMESTAMP;", migrationTable)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Status returns last migration name
+func (t *Entity) Status() (string, error) {
+	result := &Entity{}
+	err := t.sql.Get(result, fmt.Sprintf("SELECT * FROM %v ORDER BY id DESC LIMIT 1;", migrationTable))
+
+	// If no rows, then set to nil
+	if err == sql.ErrNoRows {
+		err = nil
+	}
+
+	return result.Name, err
+}
+
+// AppliedChecksums returns the stored checksum of every applied migration,
+// keyed by name.
+func (t *Entity) AppliedChecksums() (map[string]string, error) {
+	var rows []Entity
+	err := t.sql.Select(&rows, fmt.Sprintf("SELECT name, checksum FROM %v;", migrationTable))
+	if err != nil {
+		return nil, err
+	}
+
+	checksums := make(map[string]string, len(rows))
+	for _, row := range rows {
+		checksums[row.Name] = row.Checksum
+	}
+
+	return checksums, nil
+}
+
+// Migrate runs a query and returns error
+func (t *Entity) Migrate(qry string) error {
+	_, err := t.sql.Exec(qry)
+	return err
+}
+
+// RecordUp adds a record to the database, storing the SHA-256 checksum of
+// the migration's .up.sql file so future runs can detect edits to it.
+func (t *Entity) RecordUp(name string, checksum string) error {
+	_, err := t.sql.Exec(fmt.Sprintf("INSERT INTO %v (name, checksum) VALUES (?, ?);", migrationTable), name, checksum)
+	return err
+}
+
+// RecordDown removes a record from the database.
+//
+// Unlike MySQL, SQLite's INTEGER PRIMARY KEY rowid alias is never reused, so
+// there is no AUTO_INCREMENT value to rewind here.
+func (t *Entity) RecordDown(name string) error {
+	_, err := t.sql.Exec(fmt.Sprintf("DELETE FROM %v WHERE name = ?;", migrationTable), name)
+	return err
+}
+
+// Entity defines the migration table
+type Entity struct {
+	ID        uint32       `db:"id"`
+	Name      string       `db:"name"`
+	Checksum  string       `db:"checksum"`
+	CreatedAt time.Time    `db:"created_at"`
+	AppliedAt sql.NullTime `db:"applied_at"`
+	sql       *sqlx.DB
+}
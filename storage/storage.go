@@ -3,14 +3,26 @@ package storage
 
 import (
 	"encoding/json"
+	"fmt"
 
 	"github.com/stefanwuthrich/core/jsonconfig"
 	"github.com/stefanwuthrich/core/storage/driver/mysql"
+	"github.com/stefanwuthrich/core/storage/driver/postgres"
+	"github.com/stefanwuthrich/core/storage/driver/sqlite"
+	"github.com/stefanwuthrich/core/storage/migration"
+	migrationmysql "github.com/stefanwuthrich/core/storage/migration/mysql"
+	migrationpostgres "github.com/stefanwuthrich/core/storage/migration/postgres"
+	migrationsqlite "github.com/stefanwuthrich/core/storage/migration/sqlite"
 )
 
 // Info contains the database connection information for the different storage.
 type Info struct {
-	MySQL mysql.Info `json:"MySQL"`
+	// Driver selects which of the sections below is active. Supported
+	// values are "mysql" (the default), "sqlite" and "postgres".
+	Driver     string        `json:"Driver"`
+	MySQL      mysql.Info    `json:"MySQL"`
+	SQLite     sqlite.Info   `json:"SQLite"`
+	PostgreSQL postgres.Info `json:"PostgreSQL"`
 }
 
 // ParseJSON unmarshals bytes to structs.
@@ -29,3 +41,24 @@ func LoadConfig(configFile string) (*Info, error) {
 	// Return the configuration
 	return config, err
 }
+
+// Configuration wraps a loaded Info and dispatches to the dialect-specific
+// migration package selected by Driver.
+type Configuration struct {
+	Info
+}
+
+// New connects to the configured driver and returns a ready-to-use
+// migration.Info.
+func (c Configuration) New() (*migration.Info, error) {
+	switch c.Driver {
+	case "", "mysql":
+		return migrationmysql.Configuration{Info: c.MySQL}.New()
+	case "sqlite":
+		return migrationsqlite.Configuration{Info: c.SQLite}.New()
+	case "postgres":
+		return migrationpostgres.Configuration{Info: c.PostgreSQL}.New()
+	default:
+		return nil, fmt.Errorf("storage: unknown driver %q", c.Driver)
+	}
+}
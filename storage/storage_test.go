@@ -0,0 +1,29 @@
+package storage
+
+import "testing"
+
+func TestInfoParseJSON(t *testing.T) {
+	t.Run("valid", func(t *testing.T) {
+		var c Info
+		if err := c.ParseJSON([]byte(`{"Driver": "sqlite"}`)); err != nil {
+			t.Fatalf("ParseJSON() error: %v", err)
+		}
+		if c.Driver != "sqlite" {
+			t.Errorf("c.Driver = %q, want %q", c.Driver, "sqlite")
+		}
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		var c Info
+		if err := c.ParseJSON([]byte(`{not json`)); err == nil {
+			t.Fatal("expected an error for invalid JSON, got nil")
+		}
+	})
+}
+
+func TestConfigurationNewUnknownDriver(t *testing.T) {
+	c := Configuration{Info: Info{Driver: "oracle"}}
+	if _, err := c.New(); err == nil {
+		t.Fatal("expected an error for an unknown driver, got nil")
+	}
+}